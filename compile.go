@@ -0,0 +1,73 @@
+package inject
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// handlerType is the reflect.Type of the http.Handler interface.
+var handlerType = reflect.TypeOf((*http.Handler)(nil)).Elem()
+
+// Compiler is implemented by Injectors that can pre-resolve a function's
+// arguments once and hand back a closure that reuses them, instead of
+// re-walking the function's signature on every call the way Inject does.
+type Compiler interface {
+	// Compile checks fn the same way Inject does and resolves its
+	// arguments once, up front. The returned closure calls fn with those
+	// already-resolved arguments every time it's invoked, so a router can
+	// call it on a hot path (or even per-request) without paying for
+	// reflection on each call.
+	Compile(fn interface{}) (func() (interface{}, error), error)
+
+	// CompileHandler is Compile for the common case: it additionally
+	// requires fn's return type to implement http.Handler (which
+	// http.HandlerFunc does), calls the compiled closure once to build
+	// the handler, and returns it directly.
+	CompileHandler(fn interface{}) (http.Handler, error)
+}
+
+func (n *needle) Compile(fn interface{}) (func() (interface{}, error), error) {
+	typ := reflect.TypeOf(fn)
+	val := reflect.ValueOf(fn)
+	if val.Kind() != reflect.Func {
+		return nil, fmt.Errorf("arg is a %s, not a Func: %v", val.Kind().String(), fn)
+	}
+	if err := validateReturns(typ, fn); err != nil {
+		return nil, err
+	}
+	n.mu.Lock()
+	args, err := n.buildArgs(typ, fn, map[reflect.Type]bool{})
+	n.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return func() (interface{}, error) {
+		results := val.Call(args)
+		result, err := splitResults(results)
+		return result.Interface(), err
+	}, nil
+}
+
+func (n *needle) CompileHandler(fn interface{}) (http.Handler, error) {
+	typ := reflect.TypeOf(fn)
+	if typ == nil || typ.Kind() != reflect.Func || typ.NumOut() < 1 {
+		return nil, fmt.Errorf("arg is not a Func returning http.Handler: %#v", fn)
+	}
+	if out := typ.Out(0); !out.Implements(handlerType) {
+		return nil, fmt.Errorf("cannot compile a handler from a function returning %s, want an http.Handler: %#v", out, fn)
+	}
+	call, err := n.Compile(fn)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call()
+	if err != nil {
+		return nil, err
+	}
+	h, ok := res.(http.Handler)
+	if !ok {
+		return nil, fmt.Errorf("constructor for %#v returned a nil http.Handler", fn)
+	}
+	return h, nil
+}
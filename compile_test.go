@@ -0,0 +1,72 @@
+package inject
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	di, _ := New(A(1), B(2))
+	var calls int
+	call, err := di.(Compiler).Compile(func(a A, b B) int {
+		calls++
+		return int(a) + int(b)
+	})
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		res, err := call()
+		if err != nil {
+			t.Fatalf("call error: %v", err)
+		}
+		if res.(int) != 3 {
+			t.Errorf("expected 3, got %v", res)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected fn to run 3 times, got %d", calls)
+	}
+}
+
+func TestCompileMissingType(t *testing.T) {
+	di, _ := New(A(1))
+	if _, err := di.(Compiler).Compile(func(i int) int { return i }); err == nil {
+		t.Errorf("expected compile to fail for a missing type")
+	}
+}
+
+func TestCompileHandler(t *testing.T) {
+	di, _ := New(A(42))
+	handler := func(a A) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(w, "%d", a)
+		}
+	}
+	h, err := di.(Compiler).CompileHandler(handler)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Body.String() != "42" {
+		t.Errorf("expected 42, got %q", rec.Body.String())
+	}
+}
+
+func TestCompileHandlerWrongReturnType(t *testing.T) {
+	di, _ := New(A(0))
+	if _, err := di.(Compiler).CompileHandler(func(a A) int { return int(a) }); err == nil {
+		t.Errorf("expected an error for a function not returning http.Handler")
+	}
+}
+
+func TestCompileHandlerNilHandler(t *testing.T) {
+	di, _ := New(A(0))
+	_, err := di.(Compiler).CompileHandler(func(a A) http.Handler { return nil })
+	if err == nil {
+		t.Errorf("expected an error for a constructor returning a nil http.Handler")
+	}
+}
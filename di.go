@@ -22,6 +22,46 @@ the `Injector` interface into your library and your users can pass any
 compatible implementation (like this one) to use it. Keep your dependency tree
 clean, and still give your users injection!
 
+As well as plain values, New also accepts constructor functions. A constructor
+is any arg that's a func following the same return-value rules as a function
+passed to Inject (one value, optionally followed by an error); it's registered
+against its return type and called lazily, at most once per Injector, the
+first time something needs a value of that type. This lets you build up a
+provider chain the same way nject does, e.g.
+`inject.New(NewDB, NewTemplate, logger)`, without giving up the simple
+flat-map injector for users who only ever pass plain values.
+
+Treating every func as a provider means a func-typed *value*, such as
+`type Middleware func(http.Handler) http.Handler`, can't be passed to New
+directly and looked up by its own type; wrap it with AsValue to register it
+as a plain value instead.
+
+Exact-type matches are tried first, but if a parameter's type is an
+interface and nothing was registered under that exact interface type, the
+needle is scanned for the one registered value or provider whose type
+implements it (e.g. a function taking io.Reader can receive a registered
+*os.File). If more than one registered type implements the interface,
+Inject fails rather than guessing, and reports every candidate.
+
+A variadic final parameter is filled with every registered value (or
+provider result) assignable to its element type, in place of requiring an
+exact match, so a function like `func(mw ...Middleware) http.Handler` can
+receive every registered Middleware in one call. Functions with more than
+one non-error return value aren't accepted by Inject itself, which keeps
+its one-value-plus-error contract; use MultiInjector.InjectMulti for those.
+
+New's Injector also implements Lifecycle: any registered value or provider
+result implementing Healthcheckable or Shutdownable is picked up by
+Lifecycle.HealthCheck and Lifecycle.Shutdown respectively, the latter torn
+down in the reverse of construction order so dependents shut down before
+their dependencies.
+
+It also implements Compiler, for callers that want to pay Inject's
+reflection cost once and reuse the result: Compile resolves fn's arguments
+up front and hands back a closure that calls fn with them on every
+invocation, and CompileHandler does the same for the common case of a
+constructor that returns an http.Handler.
+
 Under MIT license.
 */
 package inject
@@ -29,6 +69,9 @@ package inject
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -40,25 +83,223 @@ type Injector interface {
 	// was a problem.
 	//
 	// fn can take any number of arguments but it can only
-	// return one value in addition to an optional error.
+	// return one value in addition to an optional error. A
+	// variadic final argument is filled with every registered
+	// value assignable to its element type.
 	Inject(fn interface{}) (interface{}, error)
 }
 
+// MultiInjector is implemented by Injectors that also support calling
+// functions with more than one non-error return value, which Inject itself
+// rejects to keep its one-value-plus-error contract. Like Inject, a
+// variadic final argument is filled with every registered value assignable
+// to its element type.
+type MultiInjector interface {
+	// InjectMulti calls fn the same way Inject does, but returns every
+	// result in order. A trailing error return is consumed the same way
+	// it is for Inject and is not included in the returned slice.
+	InjectMulti(fn interface{}) ([]interface{}, error)
+}
+
+// errType is the reflect.Type of the built-in error interface.
+var errType = reflect.TypeOf((*error)(nil)).Elem()
+
+// ErrMissing is wrapped into the error returned when a function or provider
+// parameter's type has no registered value, provider, or unique interface
+// implementation. Callers that want to tell "nothing is registered for this
+// type" apart from a real provider failure or an ambiguous interface match
+// (neither of which wrap ErrMissing) can check for it with errors.Is.
+var ErrMissing = errors.New("inject: no value registered for type")
+
 // New returns a new Injector using the args for injection.
 //
-// args are the values injected into functions passed to Inject.
-// There can only be one value of a given type per Injector.
+// args are the values injected into functions passed to Inject. There can
+// only be one value (or provider, see below) of a given type per Injector.
+//
+// Any arg that is itself a func is instead registered as a provider, keyed
+// by its return type, and is only called the first time that type is
+// actually needed by an Inject call (directly or as a dependency of another
+// provider). A provider follows the same return-value rules as a function
+// passed to Inject: it returns one value, optionally followed by an error.
+// A provider's own parameters are resolved the same way, recursively, and
+// its result is memoized so it runs at most once per Injector.
 func New(args ...interface{}) (Injector, error) {
-	n := needle{}
+	n := &needle{values: map[reflect.Type]reflect.Value{}, providers: map[reflect.Type]reflect.Value{}}
 	for _, v := range args {
-		typ, val := reflect.TypeOf(v), reflect.ValueOf(v)
-		if old, ok := n[typ]; ok {
-			oi, vi := old.Interface(), val.Interface()
-			return nil, fmt.Errorf("cannot inject two values of the same type. first: %#v (%T), second: %#v (%T)", oi, oi, vi, vi)
+		if ov, ok := v.(valueOverride); ok {
+			if err := addValue(n, reflect.ValueOf(ov.v)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		val := reflect.ValueOf(v)
+		if val.Kind() == reflect.Func {
+			if err := addProvider(n, val, v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := addValue(n, val); err != nil {
+			return nil, err
+		}
+	}
+	if err := detectCycles(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// valueOverride marks a value passed to New as a plain value even if it's a
+// func, so it's registered by its exact type instead of as a lazy provider.
+// Construct one with AsValue.
+type valueOverride struct{ v interface{} }
+
+// AsValue wraps v so New registers it as a plain value of v's exact type,
+// even if v is a func. Use this for a func-typed value, such as
+// `type Middleware func(http.Handler) http.Handler`, that should be looked
+// up by its own type rather than treated as a provider for its return type.
+func AsValue(v interface{}) interface{} {
+	return valueOverride{v}
+}
+
+func addValue(n *needle, val reflect.Value) error {
+	typ := val.Interface()
+	t := val.Type()
+	if old, ok := n.values[t]; ok {
+		oi, vi := old.Interface(), typ
+		return fmt.Errorf("cannot inject two values of the same type. first: %#v (%T), second: %#v (%T)", oi, oi, vi, vi)
+	}
+	if _, ok := n.providers[t]; ok {
+		return fmt.Errorf("cannot inject a value and a provider for the same type: %s", t)
+	}
+	n.values[t] = val
+	n.order = append(n.order, t)
+	return nil
+}
+
+func addProvider(n *needle, val reflect.Value, v interface{}) error {
+	typ := val.Type()
+	if err := validateReturns(typ, v); err != nil {
+		return err
+	}
+	out := typ.Out(0)
+	if _, ok := n.providers[out]; ok {
+		return fmt.Errorf("cannot register two providers for the same type: %s", out)
+	}
+	if _, ok := n.values[out]; ok {
+		return fmt.Errorf("cannot inject a value and a provider for the same type: %s", out)
+	}
+	n.providers[out] = val
+	return nil
+}
+
+// validateReturns checks that typ follows the return-value rules shared by
+// Inject targets and providers: one value, optionally followed by an error.
+func validateReturns(typ reflect.Type, fn interface{}) error {
+	if typ.NumOut() > 2 {
+		return fmt.Errorf("cannot inject function with more than 2 return values: %#v", fn)
+	}
+	if typ.NumOut() == 2 && typ.Out(1) != errType {
+		return fmt.Errorf("cannot inject function with a non-error second return value: %s. %#v", typ.Out(1).String(), fn)
+	}
+	if typ.NumOut() < 1 {
+		return fmt.Errorf("cannot inject function with no return values: %#v", fn)
+	}
+	return nil
+}
+
+// detectCycles walks the provider graph (edges are a provider's parameter
+// types that are themselves produced by another provider) and returns an
+// error describing the cycle if one exists.
+//
+// A parameter type that's an interface isn't resolved by an exact provider
+// match, but may still route to one through the same interface-satisfying
+// fallback resolve uses at runtime (see resolveInterface): if exactly one
+// registered value or provider implements it, that's where the call would
+// actually go. detectCycles simulates that same fallback so a cycle that's
+// only reachable through it is still caught here instead of overflowing the
+// stack at runtime. An ambiguous match (more than one implementer) isn't
+// treated as an edge, since resolveInterface errors out on it directly
+// rather than recursing into either candidate.
+func detectCycles(n *needle) error {
+	providers := n.providers
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := map[reflect.Type]int{}
+	var path []reflect.Type
+
+	var visit func(t reflect.Type) error
+	visit = func(t reflect.Type) error {
+		switch color[t] {
+		case black:
+			return nil
+		case gray:
+			cycle := append(append([]reflect.Type{}, path...), t)
+			return fmt.Errorf("provider cycle detected: %s", cycleString(cycle))
+		}
+		prov, ok := providers[t]
+		if !ok {
+			return nil
+		}
+		color[t] = gray
+		path = append(path, t)
+		provType := prov.Type()
+		for i := 0; i < provType.NumIn(); i++ {
+			want := provType.In(i)
+			if _, ok := providers[want]; !ok && want.Kind() == reflect.Interface {
+				if impl, ok := uniqueImplementer(n, want); ok {
+					want = impl
+				}
+			}
+			if err := visit(want); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		color[t] = black
+		return nil
+	}
+
+	for t := range providers {
+		if err := visit(t); err != nil {
+			return err
 		}
-		n[typ] = val
 	}
-	return &n, nil
+	return nil
+}
+
+// uniqueImplementer reports the single registered value or provider type
+// that implements want, mirroring the candidate search resolveInterface
+// does at runtime. It reports ok == false if no type implements want, or if
+// more than one does (an ambiguous match isn't a reliable edge to follow).
+func uniqueImplementer(n *needle, want reflect.Type) (reflect.Type, bool) {
+	var candidate reflect.Type
+	found := 0
+	for t := range n.values {
+		if t.Implements(want) {
+			candidate, found = t, found+1
+		}
+	}
+	for t := range n.providers {
+		if t.Implements(want) {
+			candidate, found = t, found+1
+		}
+	}
+	if found != 1 {
+		return nil, false
+	}
+	return candidate, true
+}
+
+func cycleString(cycle []reflect.Type) string {
+	names := make([]string, len(cycle))
+	for i, t := range cycle {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
 }
 
 // Must can wrap Inject and panics if err is not nil
@@ -69,42 +310,255 @@ func Must(i interface{}, err error) interface{} {
 	return i
 }
 
-type needle map[reflect.Type]reflect.Value
+// needle holds the plain values and lazy providers registered with New.
+type needle struct {
+	// mu guards values, providers, and order, all of which are mutated
+	// lazily as providers are constructed: it's held for the duration of
+	// Inject, InjectMulti, and Compile so a provider is only ever
+	// constructed once even if those are called concurrently, and by
+	// HealthCheck/Shutdown just long enough to snapshot them, so a slow
+	// component's health check or shutdown doesn't block unrelated calls.
+	mu        sync.Mutex
+	values    map[reflect.Type]reflect.Value
+	providers map[reflect.Type]reflect.Value
+	// order records the type of every value as it becomes available,
+	// whether passed to New directly or constructed lazily by a provider,
+	// so Shutdown can tear components down in the reverse order.
+	order []reflect.Type
+}
 
-func (n needle) Inject(fn interface{}) (interface{}, error) {
+func (n *needle) Inject(fn interface{}) (interface{}, error) {
 	typ := reflect.TypeOf(fn)
 	val := reflect.ValueOf(fn)
-	var err error
 	// check the function for compatibility
 	if val.Kind() != reflect.Func {
 		return nil, fmt.Errorf("arg is a %s, not a Func: %v", val.Kind().String(), fn)
 	}
-	if typ.NumOut() > 2 {
-		return nil, fmt.Errorf("cannot inject function with more than 2 return values: %#v", fn)
+	if err := validateReturns(typ, fn); err != nil {
+		return nil, err
 	}
-	if typ.NumOut() == 2 && typ.Out(1) != reflect.TypeOf(&err).Elem() {
-		return nil, fmt.Errorf("cannot inject function with a non-error second return value: %s. %#v", typ.Out(1).String(), fn)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	args, err := n.buildArgs(typ, fn, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
 	}
-	if typ.NumOut() < 1 {
-		return nil, fmt.Errorf("cannot inject function with no return values: %#v", fn)
+	// call the function
+	results := val.Call(args)
+	result, err := splitResults(results)
+	return result.Interface(), err
+}
+
+func (n *needle) InjectMulti(fn interface{}) ([]interface{}, error) {
+	typ := reflect.TypeOf(fn)
+	val := reflect.ValueOf(fn)
+	if val.Kind() != reflect.Func {
+		return nil, fmt.Errorf("arg is a %s, not a Func: %v", val.Kind().String(), fn)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	args, err := n.buildArgs(typ, fn, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	results := val.Call(args)
+	return splitResultsMulti(results, typ)
+}
+
+// buildArgs resolves the arguments fn needs, in order. If typ is variadic,
+// its final parameter is filled with every registered value (or provider
+// result) assignable to the parameter's element type, instead of requiring
+// an exact match for the slice type itself.
+//
+// resolving tracks the provider types currently under construction for this
+// call, so a provider that ends up requiring itself is reported as an error
+// instead of recursing forever. New's static detectCycles already rejects
+// such cycles, including ones routed through the interface-satisfying
+// fallback; this is a guard behind it. Callers pass a fresh map.
+func (n *needle) buildArgs(typ reflect.Type, fn interface{}, resolving map[reflect.Type]bool) ([]reflect.Value, error) {
+	numIn := typ.NumIn()
+	fixed := numIn
+	if typ.IsVariadic() {
+		fixed--
+	}
+	args := make([]reflect.Value, 0, numIn)
+	for i := 0; i < fixed; i++ {
+		want := typ.In(i)
+		arg, ok, err := n.resolve(want, resolving)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("fn requires a type that's missing: %s. %#v: %w", want.String(), fn, ErrMissing)
+		}
+		args = append(args, arg)
 	}
-	// build the arguments
-	args := make([]reflect.Value, typ.NumIn())
+	if typ.IsVariadic() {
+		extra, err := n.collectAssignable(typ.In(numIn-1).Elem(), resolving)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, extra...)
+	}
+	return args, nil
+}
+
+// collectAssignable gathers every registered value (or provider result,
+// constructed on demand) whose type is assignable to elemType, for filling
+// a variadic final parameter.
+func (n *needle) collectAssignable(elemType reflect.Type, resolving map[reflect.Type]bool) ([]reflect.Value, error) {
+	seen := map[reflect.Type]bool{}
+	var types []reflect.Type
+	for t := range n.values {
+		if t.AssignableTo(elemType) && !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	for t := range n.providers {
+		if t.AssignableTo(elemType) && !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].String() < types[j].String() })
+	args := make([]reflect.Value, 0, len(types))
+	for _, t := range types {
+		arg, ok, err := n.resolve(t, resolving)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			args = append(args, arg)
+		}
+	}
+	return args, nil
+}
+
+// construct resolves a provider's own arguments (recursively resolving any
+// of its own missing dependencies through further providers), calls it, and
+// memoizes the result under typ so the provider runs at most once.
+func (n *needle) construct(typ reflect.Type, prov reflect.Value, resolving map[reflect.Type]bool) (reflect.Value, error) {
+	if resolving[typ] {
+		return reflect.Value{}, fmt.Errorf("provider cycle detected: %s requires itself, routed through an interface match", typ)
+	}
+	resolving[typ] = true
+	defer delete(resolving, typ)
+
+	provType := prov.Type()
+	args := make([]reflect.Value, provType.NumIn())
 	for i := range args {
-		arg, ok := n[typ.In(i)]
+		want := provType.In(i)
+		arg, ok, err := n.resolve(want, resolving)
+		if err != nil {
+			return reflect.Value{}, errors.Wrapf(err, "cannot construct provider for %s", typ)
+		}
 		if !ok {
-			return nil, fmt.Errorf("fn requires a type that's missing: %s. %#v", typ.In(i).String(), fn)
+			return reflect.Value{}, fmt.Errorf("provider for %s requires a type that's missing: %s: %w", typ, want, ErrMissing)
 		}
 		args[i] = arg
 	}
-	// call the function
-	results := val.Call(args)
-	// extract the optional error
+	results := prov.Call(args)
+	result, err := splitResults(results)
+	if err != nil {
+		return reflect.Value{}, errors.Wrapf(err, "cannot construct provider for %s", typ)
+	}
+	n.values[typ] = result
+	n.order = append(n.order, typ)
+	return result, nil
+}
+
+// resolve looks up a value for want: first an exact match among registered
+// values, then an exact match among providers (constructing it if needed),
+// and finally, if want is an interface, the unique registered type that
+// implements it. ok is false only when nothing at all satisfies want; a
+// non-nil err means something did match but couldn't be used (an ambiguous
+// interface or a failed provider call), and callers should stop immediately.
+func (n *needle) resolve(want reflect.Type, resolving map[reflect.Type]bool) (reflect.Value, bool, error) {
+	if v, ok := n.values[want]; ok {
+		return v, true, nil
+	}
+	if prov, ok := n.providers[want]; ok {
+		v, err := n.construct(want, prov, resolving)
+		if err != nil {
+			return reflect.Value{}, false, err
+		}
+		return v, true, nil
+	}
+	if want.Kind() == reflect.Interface {
+		return n.resolveInterface(want, resolving)
+	}
+	return reflect.Value{}, false, nil
+}
+
+// resolveInterface scans the registered values and providers for the single
+// type that implements the interface want.
+func (n *needle) resolveInterface(want reflect.Type, resolving map[reflect.Type]bool) (reflect.Value, bool, error) {
+	seen := map[reflect.Type]bool{}
+	var candidates []reflect.Type
+	for t := range n.values {
+		if t.Implements(want) && !seen[t] {
+			seen[t] = true
+			candidates = append(candidates, t)
+		}
+	}
+	for t := range n.providers {
+		if t.Implements(want) && !seen[t] {
+			seen[t] = true
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return reflect.Value{}, false, nil
+	}
+	if len(candidates) > 1 {
+		names := make([]string, len(candidates))
+		for i, t := range candidates {
+			names[i] = t.String()
+		}
+		sort.Strings(names)
+		return reflect.Value{}, false, fmt.Errorf("ambiguous type for interface %s: multiple candidates implement it: %s", want, strings.Join(names, ", "))
+	}
+	t := candidates[0]
+	if v, ok := n.values[t]; ok {
+		return v, true, nil
+	}
+	v, err := n.construct(t, n.providers[t], resolving)
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+	return v, true, nil
+}
+
+// splitResults extracts the single return value and optional error out of
+// the results of calling an Inject target or provider.
+func splitResults(results []reflect.Value) (reflect.Value, error) {
 	if len(results) == 2 {
 		// we already know that results[1] has type error
 		if i := results[1].Interface(); i != nil {
-			err = errors.Wrap(i.(error), "cannot inject")
+			return results[0], errors.Wrap(i.(error), "cannot inject")
+		}
+	}
+	return results[0], nil
+}
+
+// splitResultsMulti extracts every return value out of the results of
+// calling an InjectMulti target, consuming a trailing error return (if typ
+// has one) instead of including it in the returned slice.
+func splitResultsMulti(results []reflect.Value, typ reflect.Type) ([]interface{}, error) {
+	n := len(results)
+	hasErr := n > 0 && typ.Out(n-1) == errType
+	if hasErr {
+		n--
+	}
+	out := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		out[i] = results[i].Interface()
+	}
+	if hasErr {
+		if e := results[len(results)-1].Interface(); e != nil {
+			return out, errors.Wrap(e.(error), "cannot inject")
 		}
 	}
-	return results[0].Interface(), err
+	return out, nil
 }
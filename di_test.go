@@ -1,21 +1,29 @@
 package inject
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
 type A int
 type B int
+type C int
+type D int
 
 func TestNew(t *testing.T) {
 	di, err := New(A(0), B(1))
 	if err != nil {
 		t.Errorf("error creating injector: %v", err)
 	}
-	if n := *di.(*needle); len(n) != 2 {
-		t.Errorf("needle is the wrong length: %v", n)
+	if n := di.(*needle); len(n.values) != 2 {
+		t.Errorf("needle is the wrong length: %v", n.values)
 	}
 	di, err = New(A(0), A(1))
 	if err == nil {
@@ -23,6 +31,206 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestProvide(t *testing.T) {
+	var cCalls, dCalls int
+	newC := func(a A) C {
+		cCalls++
+		return C(int(a) + 1)
+	}
+	newD := func(c C) (D, error) {
+		dCalls++
+		return D(int(c) + 1), nil
+	}
+	di, err := New(A(1), newC, newD)
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	res, err := di.Inject(func(d D) int { return int(d) })
+	if err != nil {
+		t.Fatalf("injection error: %v", err)
+	}
+	if res.(int) != 3 {
+		t.Errorf("expected 3, got %v", res)
+	}
+	if cCalls != 1 || dCalls != 1 {
+		t.Errorf("expected each provider to run once, got cCalls=%d dCalls=%d", cCalls, dCalls)
+	}
+	// resolving D again (and C directly) must not re-run either provider.
+	if _, err := di.Inject(func(c C, d D) int { return int(c) + int(d) }); err != nil {
+		t.Fatalf("injection error: %v", err)
+	}
+	if cCalls != 1 || dCalls != 1 {
+		t.Errorf("providers were not memoized: cCalls=%d dCalls=%d", cCalls, dCalls)
+	}
+}
+
+// TestProvideConcurrent exercises the same shared lazy provider from many
+// goroutines at once. Run with -race: without locking n.values/n.providers
+// around construction, this reports a data race and cCalls ends up above 1.
+func TestProvideConcurrent(t *testing.T) {
+	var cCalls int32
+	newC := func(a A) C {
+		atomic.AddInt32(&cCalls, 1)
+		return C(int(a) + 1)
+	}
+	di, err := New(A(1), newC)
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := di.Inject(func(c C) int { return int(c) }); err != nil {
+				t.Errorf("injection error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&cCalls); got != 1 {
+		t.Errorf("expected the provider to run exactly once, got %d", got)
+	}
+}
+
+func TestProvideCycle(t *testing.T) {
+	newC := func(d D) C { return C(d) }
+	newD := func(c C) D { return D(c) }
+	if _, err := New(newC, newD); err == nil {
+		t.Errorf("new injector didn't catch a provider cycle")
+	} else if !strings.Contains(err.Error(), "provider cycle detected") {
+		t.Errorf("expected a cycle error, got: %v", err)
+	}
+}
+
+type cycleIface interface{ M() }
+
+type cycleD int
+
+func (cycleD) M() {}
+
+// TestProvideCycleThroughInterface covers a cycle that's only reachable
+// through the interface-satisfying fallback resolve falls back to, not
+// through any exact provider-to-provider edge: cycleIface has no provider
+// registered for that exact type, but cycleD is its sole implementer, so
+// resolving cycleIface for newC routes to constructing cycleD, which itself
+// depends back on newC's own return type.
+func TestProvideCycleThroughInterface(t *testing.T) {
+	newC := func(i cycleIface) C { return C(0) }
+	newD := func(c C) cycleD { return cycleD(c) }
+	if _, err := New(newC, newD); err == nil {
+		t.Errorf("new injector didn't catch a provider cycle routed through an interface match")
+	} else if !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error, got: %v", err)
+	}
+}
+
+func TestResolveInterface(t *testing.T) {
+	buf := bytes.NewBufferString("hello")
+	di, err := New(A(0), buf)
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	res, err := di.Inject(func(r io.Reader) string {
+		b, _ := io.ReadAll(r)
+		return string(b)
+	})
+	if err != nil {
+		t.Fatalf("injection error: %v", err)
+	}
+	if res.(string) != "hello" {
+		t.Errorf("expected hello, got %v", res)
+	}
+
+	di, _ = New(bytes.NewBufferString("a"), strings.NewReader("b"))
+	_, err = di.Inject(func(r io.Reader) string { return "" })
+	if err == nil {
+		t.Errorf("new injector didn't catch an ambiguous interface")
+	} else if !strings.Contains(err.Error(), "ambiguous type for interface") {
+		t.Errorf("expected an ambiguous interface error, got: %v", err)
+	}
+}
+
+type plugin interface {
+	Name() string
+}
+
+type pluginA struct{}
+
+func (pluginA) Name() string { return "a" }
+
+type pluginB struct{}
+
+func (pluginB) Name() string { return "b" }
+
+func TestInjectVariadic(t *testing.T) {
+	di, err := New(A(0), pluginA{}, pluginB{})
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	res, err := di.Inject(func(a A, ps ...plugin) []string {
+		var names []string
+		for _, p := range ps {
+			names = append(names, p.Name())
+		}
+		return names
+	})
+	if err != nil {
+		t.Fatalf("injection error: %v", err)
+	}
+	names := res.([]string)
+	sort.Strings(names)
+	if fmt.Sprint(names) != "[a b]" {
+		t.Errorf("expected both plugins collected, got %v", names)
+	}
+}
+
+func TestInjectMulti(t *testing.T) {
+	di, _ := New(A(1), B(2))
+	mi := di.(MultiInjector)
+	res, err := mi.InjectMulti(func(a A, b B) (int, string, error) {
+		return int(a) + int(b), "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("injection error: %v", err)
+	}
+	if len(res) != 2 || res[0].(int) != 3 || res[1].(string) != "ok" {
+		t.Errorf("unexpected results: %#v", res)
+	}
+
+	_, err = mi.InjectMulti(func(a A) (int, error) { return 0, fmt.Errorf("boom") })
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected the function's error to be wrapped, got: %v", err)
+	}
+}
+
+type middleware func(A) B
+
+func TestAsValue(t *testing.T) {
+	var mw middleware = func(a A) B { return B(int(a) + 1) }
+	di, err := New(A(1), AsValue(mw))
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	res, err := di.Inject(func(mw middleware) int { return int(mw(A(1))) })
+	if err != nil {
+		t.Fatalf("injection error: %v", err)
+	}
+	if res.(int) != 2 {
+		t.Errorf("expected 2, got %v", res)
+	}
+
+	// without AsValue, the same func is registered as a provider for B,
+	// not a value of type middleware.
+	di, err = New(A(1), mw)
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	if _, err := di.Inject(func(mw middleware) int { return 0 }); err == nil {
+		t.Errorf("expected a bare func to be registered as a provider, not a middleware value")
+	}
+}
+
 func TestInject(t *testing.T) {
 	di, _ := New(A(0), B(1), "foo", (*sql.DB)(nil))
 	cases := []struct {
@@ -19,16 +19,48 @@ func home(t *template.Template, db *sql.DB) http.HandlerFunc {
 	}
 }
 
-type middleware func(http.Handler) http.Handler
-
-func logMiddleware(lg *log.Logger) middleware {
-	return func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			lg.Printf("before: %s\n", req.URL.Path)
-			h.ServeHTTP(w, req)
-			lg.Println("after")
-		})
+// Middleware is implemented by every registered middleware so chain can
+// collect all of them at once via a variadic Inject parameter, regardless
+// of how many there are or what concrete type each one is.
+type Middleware interface {
+	Wrap(http.Handler) http.Handler
+}
+
+type logMiddleware struct {
+	lg *log.Logger
+}
+
+func (m logMiddleware) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.lg.Printf("before: %s\n", req.URL.Path)
+		h.ServeHTTP(w, req)
+		m.lg.Println("after")
+	})
+}
+
+type recoverMiddleware struct {
+	lg *log.Logger
+}
+
+func (m recoverMiddleware) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if r := recover(); r != nil {
+				m.lg.Printf("recovered: %v\n", r)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(w, req)
+	})
+}
+
+// chain applies every registered Middleware to h, outermost first.
+func chain(h http.HandlerFunc, mw ...Middleware) http.Handler {
+	var handler http.Handler = h
+	for i := len(mw) - 1; i >= 0; i-- {
+		handler = mw[i].Wrap(handler)
 	}
+	return handler
 }
 
 // deps gets all the dependencies and returns them in an Injector
@@ -37,7 +69,7 @@ func deps() inject.Injector {
 	tmpl := template.Must(template.New("example").Parse(`{{define "home.html"}}Hello, now it's {{.}}!{{end}}`))
 	db, _ := sql.Open("sqlite3", ":memory:")
 	lg := log.New(os.Stderr, "CUSTOM LOGGER: ", log.LstdFlags)
-	di, _ := inject.New(db, tmpl, lg)
+	di, _ := inject.New(db, tmpl, lg, recoverMiddleware{lg}, logMiddleware{lg})
 	return di
 }
 
@@ -48,7 +80,7 @@ func main() {
 	// This could all be done by your router to make it cleaner. See goji.go for
 	// an example.
 	h := inject.Must(di.Inject(home)).(http.HandlerFunc)
-	mid := inject.Must(di.Inject(logMiddleware)).(middleware)
-	http.Handle("/", mid(h))
+	handler := inject.Must(di.Inject(func(mw ...Middleware) http.Handler { return chain(h, mw...) })).(http.Handler)
+	http.Handle("/", handler)
 	http.ListenAndServe(":8080", nil)
 }
@@ -0,0 +1,114 @@
+package inject
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// Healthcheckable is implemented by values that can report their own
+// health. Any registered value or provider result implementing it is
+// checked by Lifecycle.HealthCheck.
+type Healthcheckable interface {
+	Healthcheck() error
+}
+
+// Shutdownable is implemented by values that need graceful teardown, such
+// as DBs, loggers, and HTTP servers. Any registered value or provider
+// result implementing it is stopped by Lifecycle.Shutdown.
+type Shutdownable interface {
+	Shutdown() error
+}
+
+// Lifecycle is implemented by Injectors that track the construction order
+// of their values (see New and the provider chain it builds), so they can
+// health-check and tear down the whole graph.
+type Lifecycle interface {
+	// HealthCheck calls Healthcheck on every constructed value that
+	// implements Healthcheckable and returns its result keyed by type. A
+	// component that doesn't return before ctx is done is reported with
+	// ctx.Err() instead of being left to run.
+	HealthCheck(ctx context.Context) map[reflect.Type]error
+
+	// Shutdown calls Shutdown on every constructed value that implements
+	// Shutdownable, in the reverse of the order those values were
+	// constructed (LIFO), so a component is torn down only after whatever
+	// was built on top of it. It keeps going even if a component fails or
+	// times out, honoring ctx's deadline per component the same way
+	// HealthCheck does, and returns every error it collected along the
+	// way, aggregated into one.
+	Shutdown(ctx context.Context) error
+}
+
+func (n *needle) HealthCheck(ctx context.Context) map[reflect.Type]error {
+	n.mu.Lock()
+	values := make(map[reflect.Type]reflect.Value, len(n.values))
+	for t, v := range n.values {
+		values[t] = v
+	}
+	n.mu.Unlock()
+
+	results := make(map[reflect.Type]error)
+	for t, v := range values {
+		hc, ok := v.Interface().(Healthcheckable)
+		if !ok {
+			continue
+		}
+		results[t] = runWithDeadline(ctx, hc.Healthcheck)
+	}
+	return results
+}
+
+func (n *needle) Shutdown(ctx context.Context) error {
+	n.mu.Lock()
+	values := make(map[reflect.Type]reflect.Value, len(n.values))
+	for t, v := range n.values {
+		values[t] = v
+	}
+	order := append([]reflect.Type(nil), n.order...)
+	n.mu.Unlock()
+
+	var errs []error
+	for i := len(order) - 1; i >= 0; i-- {
+		v, ok := values[order[i]]
+		if !ok {
+			continue
+		}
+		sd, ok := v.Interface().(Shutdownable)
+		if !ok {
+			continue
+		}
+		if err := runWithDeadline(ctx, sd.Shutdown); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return multiError(errs)
+}
+
+// runWithDeadline runs fn to completion, but returns ctx.Err() instead if
+// ctx is done first, so one hung component can't block the rest.
+func runWithDeadline(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// multiError aggregates the independent errors collected while shutting
+// down multiple components.
+type multiError []error
+
+func (m multiError) Error() string {
+	parts := make([]string, len(m))
+	for i, err := range m {
+		parts[i] = err.Error()
+	}
+	return strings.Join(parts, "; ")
+}
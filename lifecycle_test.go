@@ -0,0 +1,129 @@
+package inject
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type healthy struct{ err error }
+
+func (h healthy) Healthcheck() error { return h.err }
+
+type slowHealthy struct{ err error }
+
+func (h slowHealthy) Healthcheck() error {
+	time.Sleep(50 * time.Millisecond)
+	return h.err
+}
+
+type tracker struct {
+	name  string
+	order *[]string
+	slow  bool
+}
+
+func (t tracker) Shutdown() error {
+	*t.order = append(*t.order, t.name)
+	if t.slow {
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil
+}
+
+// trackerB has the same behavior as tracker under a distinct type, so a
+// test can register one directly and build the other via a provider.
+type trackerB tracker
+
+func (t trackerB) Shutdown() error { return tracker(t).Shutdown() }
+
+func TestHealthCheck(t *testing.T) {
+	di, _ := New(healthy{nil}, A(0))
+	hc := di.(Lifecycle)
+	results := hc.HealthCheck(context.Background())
+	if len(results) != 1 {
+		t.Fatalf("expected exactly one Healthcheckable component, got %d", len(results))
+	}
+	for _, err := range results {
+		if err != nil {
+			t.Errorf("expected healthy component, got %v", err)
+		}
+	}
+
+	boom := fmt.Errorf("boom")
+	di, _ = New(healthy{boom})
+	hc = di.(Lifecycle)
+	results = hc.HealthCheck(context.Background())
+	for _, err := range results {
+		if err != boom {
+			t.Errorf("expected %v, got %v", boom, err)
+		}
+	}
+}
+
+func TestHealthCheckDeadline(t *testing.T) {
+	di, _ := New(slowHealthy{})
+	hc := di.(Lifecycle)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	results := hc.HealthCheck(ctx)
+	if len(results) != 1 {
+		t.Fatalf("expected exactly one Healthcheckable component, got %d", len(results))
+	}
+	for _, err := range results {
+		if !strings.Contains(fmt.Sprint(err), context.DeadlineExceeded.Error()) {
+			t.Errorf("expected a deadline exceeded error, got %v", err)
+		}
+	}
+}
+
+func TestShutdownOrder(t *testing.T) {
+	var order []string
+	newSecond := func(a A) trackerB { return trackerB{name: "second", order: &order} }
+	di, err := New(A(0), tracker{name: "first", order: &order}, newSecond)
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	// force the provider-built component to actually be constructed
+	if _, err := di.Inject(func(trackerB) int { return 0 }); err != nil {
+		t.Fatalf("injection error: %v", err)
+	}
+	sd := di.(Lifecycle)
+	if err := sd.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "second,first" {
+		t.Errorf("expected LIFO shutdown order second,first; got %s", got)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	var order []string
+	di, _ := New(tracker{name: "slow", order: &order, slow: true})
+	sd := di.(Lifecycle)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err := sd.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("expected a deadline exceeded error, got %v", err)
+	}
+}
+
+type failingShutdownA struct{}
+
+func (failingShutdownA) Shutdown() error { return fmt.Errorf("a failed") }
+
+type failingShutdownB struct{}
+
+func (failingShutdownB) Shutdown() error { return fmt.Errorf("b failed") }
+
+func TestShutdownAggregatesErrors(t *testing.T) {
+	di, _ := New(failingShutdownA{}, failingShutdownB{})
+	sd := di.(Lifecycle)
+	err := sd.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "a failed") || !strings.Contains(err.Error(), "b failed") {
+		t.Errorf("expected shutdown error to include both failures, got %v", err)
+	}
+}
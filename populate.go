@@ -0,0 +1,113 @@
+package inject
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Populate fills the exported fields of the struct pointed to by target that
+// are tagged `inject:"..."`, using values from i.
+//
+// A field tagged `inject:""` is required: Populate returns an error if i has
+// no value (or provider, or unique interface implementation) for its type.
+// A field tagged `inject:"optional"` is skipped instead of erroring, but only
+// when the error wraps ErrMissing: nothing is registered for its type, or the
+// provider registered for it needs a type that nothing is registered for. A
+// provider that runs and returns an error, or an ambiguous interface match,
+// is never "missing" and always propagates, optional or not. A field tagged `inject:"-"` is left untouched, which is
+// useful to silence this behavior on an embedded struct that also happens to
+// carry inject tags for some other purpose.
+//
+// If a tagged field's own type has no registered value but is itself a
+// struct (or pointer to struct) containing inject-tagged fields, Populate
+// recurses into it instead of failing, allocating the pointer if needed.
+// This lets an application model its whole dependency graph as a tree of
+// structs and wire it in one call.
+func Populate(i Injector, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("inject: Populate target must be a non-nil pointer to a struct, got %T", target)
+	}
+	return populate(i, v.Elem())
+}
+
+func populate(i Injector, v reflect.Value) error {
+	t := v.Type()
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		tag, ok := field.Tag.Lookup("inject")
+		if !ok || tag == "-" {
+			continue
+		}
+		if err := populateField(i, v.Field(idx), tag == "optional"); err != nil {
+			return fmt.Errorf("inject: cannot populate field %s.%s: %w", t.Name(), field.Name, err)
+		}
+	}
+	return nil
+}
+
+func populateField(i Injector, fv reflect.Value, optional bool) error {
+	ft := fv.Type()
+	val, err := injectValue(i, ft)
+	if err == nil {
+		fv.Set(val)
+		return nil
+	}
+	// Only an error wrapping ErrMissing (nothing registered for the type, or
+	// for a dependency of its provider) is eligible for the optional/recurse
+	// fallbacks below; a provider that returns an error or an ambiguous
+	// interface match is never "missing" and always propagates.
+	if !errors.Is(err, ErrMissing) {
+		return err
+	}
+	if !isPopulatable(ft) {
+		if optional {
+			return nil
+		}
+		return err
+	}
+	target := fv
+	if ft.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(ft.Elem()))
+		}
+		target = fv.Elem()
+	}
+	return populate(i, target)
+}
+
+// injectValue asks i for a single value of type t, reusing the same
+// type-keyed resolution (exact match, provider construction, or unique
+// interface implementation) that Inject uses for function parameters.
+func injectValue(i Injector, t reflect.Type) (reflect.Value, error) {
+	identity := reflect.MakeFunc(reflect.FuncOf([]reflect.Type{t}, []reflect.Type{t}, false), func(args []reflect.Value) []reflect.Value {
+		return args
+	})
+	res, err := i.Inject(identity.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(res), nil
+}
+
+// isPopulatable reports whether t (or, if t is a pointer, the type it points
+// to) is a struct with at least one inject-tagged field, i.e. a nested
+// component Populate should recurse into rather than resolve as a whole.
+func isPopulatable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("inject"); ok {
+			return true
+		}
+	}
+	return false
+}
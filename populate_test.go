@@ -0,0 +1,94 @@
+package inject
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+type Logger struct {
+	Prefix string
+}
+
+type Store struct {
+	A A `inject:""`
+	B B `inject:"optional"`
+}
+
+type App struct {
+	Logger  *Logger `inject:""`
+	Store   Store   `inject:""`
+	Ignored B       `inject:"-"`
+}
+
+func TestPopulate(t *testing.T) {
+	lg := &Logger{Prefix: "app: "}
+	di, err := New(A(1), lg)
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	var app App
+	if err := Populate(di, &app); err != nil {
+		t.Fatalf("populate error: %v", err)
+	}
+	if app.Logger != lg {
+		t.Errorf("expected Logger to be %v, got %v", lg, app.Logger)
+	}
+	if app.Store.A != A(1) {
+		t.Errorf("expected Store.A to be 1, got %v", app.Store.A)
+	}
+	if app.Store.B != B(0) {
+		t.Errorf("expected optional Store.B to be left zero, got %v", app.Store.B)
+	}
+	if app.Ignored != B(0) {
+		t.Errorf("expected excluded field to be left zero, got %v", app.Ignored)
+	}
+}
+
+func TestPopulateMissingRequired(t *testing.T) {
+	di, _ := New(&Logger{})
+	var app App
+	err := Populate(di, &app)
+	if err == nil {
+		t.Errorf("expected an error for a missing required dependency")
+	}
+}
+
+func TestPopulateNotAPointer(t *testing.T) {
+	di, _ := New()
+	if err := Populate(di, App{}); err == nil {
+		t.Errorf("expected an error when target is not a pointer to a struct")
+	}
+}
+
+type Conn struct{}
+
+type OptionalProvider struct {
+	Conn *Conn `inject:"optional"`
+}
+
+func TestPopulateOptionalProviderFailurePropagates(t *testing.T) {
+	newConn := func() (*Conn, error) { return nil, fmt.Errorf("connection refused") }
+	di, err := New(newConn)
+	if err != nil {
+		t.Fatalf("error creating injector: %v", err)
+	}
+	var s OptionalProvider
+	if err := Populate(di, &s); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected a real provider failure to propagate even for an optional field, got %v", err)
+	}
+}
+
+type OptionalReader struct {
+	R io.Reader `inject:"optional"`
+}
+
+func TestPopulateOptionalAmbiguousInterfacePropagates(t *testing.T) {
+	di, _ := New(bytes.NewBufferString("a"), strings.NewReader("b"))
+	var s OptionalReader
+	if err := Populate(di, &s); err == nil || !strings.Contains(err.Error(), "ambiguous type for interface") {
+		t.Errorf("expected an ambiguous interface error to propagate even for an optional field, got %v", err)
+	}
+}